@@ -26,8 +26,10 @@ function BoardGameFramework() {
     // got one
     this._nextOpen = null;
 
-    // Client ID
-    this.id = '' + Date.now() + '.' + Math.round(Math.random() * 0xffffffff);
+    // Client ID: 128 random bits from a cryptographically secure source,
+    // as hex, so it can't be guessed from the time we started
+    this.id = Array.from(crypto.getRandomValues(new Uint8Array(16)),
+        b => b.toString(16).padStart(2, '0')).join('');
 
     // Last num received. -1 means there was no last num received.
     this._num = -1;
@@ -47,6 +49,10 @@ function BoardGameFramework() {
     // we're connected (in milliseconds).
     this._stablePeriod = 2000;
 
+    // The longest we'll wait before reconnecting if the server asks us
+    // to wait (in milliseconds).
+    this._maxRetryAfter = 60000;
+
     // Act on an instruction from the main app: Open, Close, Send
     this.act = function(data) {
         switch (data.instruction) {
@@ -121,9 +127,15 @@ function BoardGameFramework() {
                 // Need to reconnect; tell the app
                 top._sendConnEnv('connecting');
                 url = top._makeConnURL(top._baseURL);
-                await new Promise(r => setTimeout(r, top._delay()));
-                top.open(url);
-                return;
+                await new Promise(r => setTimeout(r,
+                    top._retryAfter(evt) + top._delay()));
+
+                // We may have been told to close, or to open another
+                // connection, while we waited
+                if (top._baseURL != null) {
+                    top.open(url);
+                    return;
+                }
             }
 
             // We accept this close
@@ -197,6 +209,22 @@ function BoardGameFramework() {
         return 750 + Math.random()*500;
     };
 
+    // The server may ask us to wait before reconnecting (for example, if
+    // it has closed the connection because it's busy) by putting
+    // "retry-after=<seconds>" in the close reason. Return that wait in
+    // milliseconds (but no more than our maximum), or 0 if the server
+    // hasn't asked.
+    this._retryAfter = function(evt) {
+        if (typeof evt.reason != 'string') {
+            return 0;
+        }
+        var match = evt.reason.match(/retry-after=(\d+(\.\d+)?)/);
+        if (!match) {
+            return 0;
+        }
+        return Math.min(parseFloat(match[1]) * 1000, this._maxRetryAfter);
+    };
+
     // Send a connection envelope to the app, but each message should
     // only be sent once in succession.
     this._sendConnEnv = function(state) {
@@ -12,6 +12,7 @@ var EmptyWebSocket = function() {
     this.onopen = function(evt){};
     this.onclose = function(evt){};
     this.onmessage = function(evt){};
+    this.close = function(){};
 }
 
 // In the tests below we have to simulate when the websocket would
@@ -60,6 +61,28 @@ test('New BGF object generates client ID', function(t) {
     t.end();
 });
 
+test('Client IDs are random hex and unique', function(t) {
+    let ids = new Set();
+    let badID = null;
+
+    for (let i = 0; i < 1000; i++) {
+        bgf = new BGF.BoardGameFramework();
+        if (!/^[0-9a-f]{32}$/.test(bgf.id)) {
+            badID = bgf.id;
+        }
+        ids.add(bgf.id);
+    }
+
+    if (badID != null) {
+        t.fail("Client ID '" + badID + "' should be 32 hex characters");
+    } else {
+        t.pass();
+    }
+    t.equal(ids.size, 1000, 'Client IDs should all be different');
+
+    t.end();
+});
+
 test('Open action creates websocket', function(t) {
     // To check we called open, and used the right URL
     let urlUsed = null;
@@ -489,6 +512,135 @@ test('Connecting with bad lastnum reconnects as new', function(t) {
     });
 });
 
+test('Reconnection waits for server-suggested retry-after', function(t) {
+    // Count the number of connections
+    let connections = 0;
+    let websocket;
+
+    // Create a BGF with a stub websocket
+    bgf = new BGF.BoardGameFramework();
+    bgf.toApp = function(env) {};
+    bgf._newWebSocket = function(url) {
+        ++connections;
+        websocket = new EmptyWebSocket();
+        return websocket;
+    };
+    bgf._delay = function(){ return 1; };
+
+    let tests = async function() {
+        // Open, then have the server close the connection, asking us
+        // to wait before retrying
+        bgf.act({ instruction: 'Open', url: 'wss://my.test.url/g/my-id'});
+        websocket.onopen({});
+        t.equal(connections, 1);
+
+        let start = Date.now();
+        await websocket.onclose(
+            { code: 4001, reason: 'Room full; retry-after=0.3' });
+        let waited = Date.now() - start;
+
+        // Check we reconnected, but only after the suggested time
+        t.equal(connections, 2);
+        t.ok(waited >= 300,
+            'Expected to wait at least 300ms, but waited ' + waited);
+    };
+
+    tests().then(result => {
+        // Tell tape we're done
+        t.end();
+    });
+});
+
+test('Server-suggested retry-after is capped', function(t) {
+    bgf = new BGF.BoardGameFramework();
+
+    t.equal(bgf._retryAfter({ reason: 'Busy; retry-after=2' }), 2000);
+    t.equal(bgf._retryAfter({ reason: 'Busy; retry-after=99999' }),
+        bgf._maxRetryAfter);
+    t.equal(bgf._retryAfter({ reason: 'Busy' }), 0);
+    t.equal(bgf._retryAfter({}), 0);
+
+    t.end();
+});
+
+test('Close during retry-after wait means no reconnection', function(t) {
+    // Count the number of connections
+    let connections = 0;
+    // Last envelope sent to application
+    let lastEnv = 'Untouched';
+    let websocket;
+
+    // Create a BGF with a stub websocket
+    bgf = new BGF.BoardGameFramework();
+    bgf.toApp = function(env) { lastEnv = env; };
+    bgf._newWebSocket = function(url) {
+        ++connections;
+        websocket = new EmptyWebSocket();
+        return websocket;
+    };
+    bgf._delay = function(){ return 1; };
+
+    let tests = async function() {
+        // Open, then have the server close the connection, asking us
+        // to wait before retrying. While we're waiting the app closes.
+        bgf.act({ instruction: 'Open', url: 'wss://my.test.url/g/my-id'});
+        websocket.onopen({});
+        t.equal(connections, 1);
+
+        let closing = websocket.onclose(
+            { code: 4001, reason: 'Room full; retry-after=0.3' });
+        bgf.act({ instruction: 'Close' });
+        await closing;
+
+        // Check we didn't reconnect, and we told the app we're disconnected
+        t.equal(connections, 1);
+        t.deepEqual(lastEnv, {connection: 'disconnected'});
+    };
+
+    tests().then(result => {
+        // Tell tape we're done
+        t.end();
+    });
+});
+
+test('Open during retry-after wait opens the new URL', function(t) {
+    // URLs connected to
+    let urls = [];
+    let websocket;
+
+    // Create a BGF with a stub websocket
+    bgf = new BGF.BoardGameFramework();
+    bgf.toApp = function(env) {};
+    bgf._newWebSocket = function(url) {
+        urls.push(url);
+        websocket = new EmptyWebSocket();
+        return websocket;
+    };
+    bgf._delay = function(){ return 1; };
+
+    let tests = async function() {
+        // Open, then have the server close the connection, asking us
+        // to wait before retrying. While we're waiting the app opens
+        // another game.
+        bgf.act({ instruction: 'Open', url: 'wss://my.test.url/g/game-1'});
+        websocket.onopen({});
+
+        let closing = websocket.onclose(
+            { code: 4001, reason: 'Room full; retry-after=0.3' });
+        bgf.act({ instruction: 'Open', url: 'wss://my.test.url/g/game-2'});
+        await closing;
+
+        // Check we connected to the new game only
+        t.equal(urls.length, 2);
+        t.equal(urls[1], 'wss://my.test.url/g/game-2?id=' + bgf.id);
+    };
+
+    tests().then(result => {
+        // Tell tape we're done
+        t.end();
+    });
+});
+
 test('Sends connecting envelope just once when reconnecting', function(t) {
     // Track the last envelope sent to the client
     let envelope = null;